@@ -0,0 +1,148 @@
+package layer4
+
+import (
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"go.uber.org/zap"
+)
+
+// Overflow policies applied when max_concurrent_handlers is saturated.
+const (
+	// OverflowBlock makes Accept wait for a free handler slot, exerting
+	// backpressure on the underlying listener.
+	OverflowBlock = "block"
+	// OverflowClose immediately closes the new connection.
+	OverflowClose = "close"
+	// OverflowRejectWith writes RejectResponse to the new connection before
+	// closing it.
+	OverflowRejectWith = "reject_with"
+)
+
+const (
+	defaultAcceptBackoffMin = 5 * time.Millisecond
+	defaultAcceptBackoffMax = time.Second
+)
+
+// AcceptBackoff configures the exponential backoff applied after repeated
+// temporary Accept errors, analogous to net/http.Server's hard-coded accept
+// retry loop.
+type AcceptBackoff struct {
+	// Min is the initial/minimum backoff duration. Default: 5ms.
+	Min caddy.Duration `json:"min,omitempty"`
+	// Max is the backoff ceiling; the delay doubles on each consecutive
+	// error up to this value. Default: 1s.
+	Max caddy.Duration `json:"max,omitempty"`
+}
+
+// backpressureConfig holds the resolved backpressure knobs for a listener.
+type backpressureConfig struct {
+	maxConcurrentHandlers int
+	overflowPolicy        string
+	rejectResponse        []byte
+	backoffMin            time.Duration
+	backoffMax            time.Duration
+}
+
+// backpressureConfig resolves the configured backpressure knobs, applying
+// defaults for anything left unset.
+func (lw *ListenerWrapper) backpressureConfig() backpressureConfig {
+	cfg := backpressureConfig{
+		overflowPolicy: OverflowClose,
+		backoffMin:     defaultAcceptBackoffMin,
+		backoffMax:     defaultAcceptBackoffMax,
+	}
+	if lw.MaxConcurrentHandlers > 0 {
+		cfg.maxConcurrentHandlers = lw.MaxConcurrentHandlers
+	}
+	if lw.OverflowPolicy != "" {
+		cfg.overflowPolicy = lw.OverflowPolicy
+	}
+	if lw.RejectResponse != "" {
+		cfg.rejectResponse = []byte(lw.RejectResponse)
+	}
+	if lw.AcceptBackoff.Min > 0 {
+		cfg.backoffMin = time.Duration(lw.AcceptBackoff.Min)
+	}
+	if lw.AcceptBackoff.Max > 0 {
+		cfg.backoffMax = time.Duration(lw.AcceptBackoff.Max)
+	}
+	return cfg
+}
+
+// connChanCapacity resolves the configured accept channel capacity, falling
+// back to the historical GOMAXPROCS(0) default.
+func (lw *ListenerWrapper) connChanCapacity() int {
+	if lw.ConnChanCapacity > 0 {
+		return lw.ConnChanCapacity
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// acquireHandlerSlot reserves a slot to run conn through l.handle, applying
+// the configured overflow policy when max_concurrent_handlers is saturated.
+// It reports whether the caller should proceed to handle conn.
+func (l *listener) acquireHandlerSlot(conn net.Conn) bool {
+	if l.handlerSem == nil {
+		return true
+	}
+
+	select {
+	case l.handlerSem <- struct{}{}:
+		return true
+	default:
+	}
+
+	switch l.backpressure.overflowPolicy {
+	case OverflowBlock:
+		// Not a rejection: the caller just waits for a free slot and is
+		// still handled, so layer4_rejected_total must not count it. But a
+		// conn stuck here is invisible to wg/activeConns, so it must still
+		// bail out the moment the listener starts draining instead of
+		// blocking past Cleanup's drain timeout.
+		select {
+		case l.handlerSem <- struct{}{}:
+			return true
+		case <-l.shutdownCtx.Done():
+			conn.Close()
+			return false
+		}
+	case OverflowRejectWith:
+		metrics.rejectedTotal.Inc()
+		if len(l.backpressure.rejectResponse) > 0 {
+			_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_, _ = conn.Write(l.backpressure.rejectResponse)
+		}
+		conn.Close()
+		return false
+	default: // OverflowClose
+		metrics.rejectedTotal.Inc()
+		conn.Close()
+		return false
+	}
+}
+
+// releaseHandlerSlot frees a slot reserved by acquireHandlerSlot.
+func (l *listener) releaseHandlerSlot() {
+	if l.handlerSem != nil {
+		<-l.handlerSem
+	}
+}
+
+// nextBackoff doubles delay up to the configured ceiling.
+func (l *listener) nextBackoff(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > l.backpressure.backoffMax {
+		delay = l.backpressure.backoffMax
+	}
+	return delay
+}
+
+func (l *listener) logTemporaryAcceptError(err error, delay time.Duration) {
+	l.logger.Error("temporary error accepting connection",
+		zap.Error(err),
+		zap.Duration("retry_in", delay),
+	)
+}
@@ -0,0 +1,181 @@
+package layer4
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func init() {
+	// registerMetrics is guarded by a sync.Once, so this only takes effect
+	// once per test binary; it just needs to happen before any test reads
+	// the package-level metrics.
+	registerMetrics(prometheus.NewRegistry())
+}
+
+func TestAcquireHandlerSlotUnlimitedAlwaysSucceeds(t *testing.T) {
+	l := &listener{logger: zap.NewNop()}
+	if !l.acquireHandlerSlot(&fakeConn{}) {
+		t.Fatal("expected acquireHandlerSlot to succeed when no semaphore is configured")
+	}
+}
+
+func TestAcquireHandlerSlotBlockPolicyDoesNotCountAsRejected(t *testing.T) {
+	l := &listener{
+		logger:       zap.NewNop(),
+		backpressure: backpressureConfig{overflowPolicy: OverflowBlock},
+		handlerSem:   make(chan struct{}, 1),
+	}
+	l.handlerSem <- struct{}{} // saturate the only slot
+
+	before := testutil.ToFloat64(metrics.rejectedTotal)
+
+	done := make(chan bool, 1)
+	go func() { done <- l.acquireHandlerSlot(&fakeConn{}) }()
+
+	// Give the blocked acquire a moment to start waiting before freeing the
+	// slot, so this actually exercises the blocking path rather than racing
+	// a slot that's already free.
+	time.Sleep(10 * time.Millisecond)
+	<-l.handlerSem
+
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatal("expected acquireHandlerSlot to return true under OverflowBlock")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquireHandlerSlot never returned after a slot freed up")
+	}
+
+	if after := testutil.ToFloat64(metrics.rejectedTotal); after != before {
+		t.Fatalf("rejectedTotal changed under OverflowBlock: before=%v after=%v", before, after)
+	}
+}
+
+func TestAcquireHandlerSlotClosePolicyRejectsAndCounts(t *testing.T) {
+	l := &listener{
+		logger:       zap.NewNop(),
+		backpressure: backpressureConfig{overflowPolicy: OverflowClose},
+		handlerSem:   make(chan struct{}, 1),
+	}
+	l.handlerSem <- struct{}{}
+
+	before := testutil.ToFloat64(metrics.rejectedTotal)
+	conn := &fakeConn{}
+
+	if l.acquireHandlerSlot(conn) {
+		t.Fatal("expected acquireHandlerSlot to return false under OverflowClose when saturated")
+	}
+	if !conn.isClosed() {
+		t.Fatal("expected the connection to be closed under OverflowClose")
+	}
+	if after := testutil.ToFloat64(metrics.rejectedTotal); after != before+1 {
+		t.Fatalf("expected rejectedTotal to increment by exactly 1, before=%v after=%v", before, after)
+	}
+}
+
+func TestAcquireHandlerSlotRejectWithWritesResponseAndCounts(t *testing.T) {
+	l := &listener{
+		logger: zap.NewNop(),
+		backpressure: backpressureConfig{
+			overflowPolicy: OverflowRejectWith,
+			rejectResponse: []byte("busy"),
+		},
+		handlerSem: make(chan struct{}, 1),
+	}
+	l.handlerSem <- struct{}{}
+
+	before := testutil.ToFloat64(metrics.rejectedTotal)
+	conn := &fakeConn{}
+
+	if l.acquireHandlerSlot(conn) {
+		t.Fatal("expected acquireHandlerSlot to return false under OverflowRejectWith when saturated")
+	}
+	if string(conn.writtenBytes()) != "busy" {
+		t.Fatalf("expected reject response to be written, got %q", conn.writtenBytes())
+	}
+	if !conn.isClosed() {
+		t.Fatal("expected the connection to be closed under OverflowRejectWith")
+	}
+	if after := testutil.ToFloat64(metrics.rejectedTotal); after != before+1 {
+		t.Fatalf("expected rejectedTotal to increment by exactly 1, before=%v after=%v", before, after)
+	}
+}
+
+// TestAcquireHandlerSlotBlockPolicyBailsOutOnShutdown is a regression test
+// for a conn stuck waiting for a handler slot under OverflowBlock: since it
+// never reaches wg.Add/trackConn, shutdown's drain-and-force-close can't see
+// it, so acquireHandlerSlot must itself give up and close the conn as soon
+// as the listener starts draining instead of blocking indefinitely.
+func TestAcquireHandlerSlotBlockPolicyBailsOutOnShutdown(t *testing.T) {
+	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	l := &listener{
+		logger:       zap.NewNop(),
+		backpressure: backpressureConfig{overflowPolicy: OverflowBlock},
+		handlerSem:   make(chan struct{}, 1),
+		shutdownCtx:  shutdownCtx,
+	}
+	l.handlerSem <- struct{}{} // saturate the only slot, nothing ever frees it
+
+	done := make(chan bool, 1)
+	conn := &fakeConn{}
+	go func() { done <- l.acquireHandlerSlot(conn) }()
+
+	time.Sleep(10 * time.Millisecond)
+	shutdownCancel()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("expected acquireHandlerSlot to give up once the listener is draining")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquireHandlerSlot never returned after shutdownCtx was canceled")
+	}
+
+	if !conn.isClosed() {
+		t.Fatal("expected the conn to be closed when acquireHandlerSlot bails out on shutdown")
+	}
+}
+
+func TestReleaseHandlerSlotFreesSlotForReuse(t *testing.T) {
+	l := &listener{
+		logger:       zap.NewNop(),
+		backpressure: backpressureConfig{overflowPolicy: OverflowClose},
+		handlerSem:   make(chan struct{}, 1),
+	}
+
+	if !l.acquireHandlerSlot(&fakeConn{}) {
+		t.Fatal("expected the first acquire to succeed")
+	}
+	l.releaseHandlerSlot()
+
+	if !l.acquireHandlerSlot(&fakeConn{}) {
+		t.Fatal("expected a slot freed by releaseHandlerSlot to be reusable")
+	}
+}
+
+func TestNextBackoffDoublesUpToMax(t *testing.T) {
+	l := &listener{backpressure: backpressureConfig{
+		backoffMin: 5 * time.Millisecond,
+		backoffMax: 20 * time.Millisecond,
+	}}
+
+	d := l.nextBackoff(l.backpressure.backoffMin)
+	if d != 10*time.Millisecond {
+		t.Fatalf("expected backoff to double to 10ms, got %v", d)
+	}
+	d = l.nextBackoff(d)
+	if d != 20*time.Millisecond {
+		t.Fatalf("expected backoff to double to 20ms, got %v", d)
+	}
+	d = l.nextBackoff(d)
+	if d != 20*time.Millisecond {
+		t.Fatalf("expected backoff to cap at 20ms, got %v", d)
+	}
+}
@@ -0,0 +1,76 @@
+package layer4
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// fakeConn is a minimal net.Conn test double that records the handful of
+// calls the timeout/backpressure logic makes, without needing a real socket.
+type fakeConn struct {
+	net.Conn // nil: only the methods below are implemented
+
+	mu           sync.Mutex
+	closed       bool
+	written      []byte
+	writeErr     error
+	readDeadline time.Time
+	readErr      error // returned by Read; defaults to io.EOF when nil
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writeErr != nil {
+		return 0, c.writeErr
+	}
+	c.written = append(c.written, b...)
+	return len(b), nil
+}
+
+func (c *fakeConn) writtenBytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.written...)
+}
+
+func (c *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (c *fakeConn) SetReadDeadline(d time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = d
+	return nil
+}
+
+func (c *fakeConn) Read([]byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.readErr != nil {
+		return 0, c.readErr
+	}
+	return 0, io.EOF
+}
+
+// fakeTimeoutError is a net.Error that reports Timeout() == true, simulating
+// what net.Conn.Read returns once a deadline set by SetReadDeadline elapses.
+type fakeTimeoutError struct{}
+
+func (fakeTimeoutError) Error() string   { return "i/o timeout" }
+func (fakeTimeoutError) Timeout() bool   { return true }
+func (fakeTimeoutError) Temporary() bool { return true }
@@ -0,0 +1,48 @@
+package layer4
+
+import (
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultKeepAliveIdle     = 2 * time.Minute
+	defaultKeepAliveInterval = 15 * time.Second
+	defaultKeepAliveCount    = 9
+)
+
+// keepAliveConfig holds the resolved TCP keepalive tuning applied to accepted connections.
+type keepAliveConfig struct {
+	enabled  bool
+	idle     time.Duration
+	interval time.Duration
+	count    int
+}
+
+// setKeepAlive enables TCP keepalive on conn and, where the OS supports it,
+// tunes the idle/interval/count parameters. Tuning that the OS rejects is
+// skipped with a debug log rather than an error. Knobs for which the platform
+// has no option are skipped without a log: the probe count on Windows, and
+// all three on platforms where setKeepAliveParameters is a no-op.
+func (l *listener) setKeepAlive(conn tcpConnection) {
+	cfg := l.keepAlive
+	if !cfg.enabled {
+		return
+	}
+
+	if err := conn.SetKeepAlive(true); err != nil {
+		l.logger.Debug("unable to enable keepalive for connection", zap.Error(err))
+		return
+	}
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+
+	if err := setKeepAliveParameters(tcpConn, cfg); err != nil {
+		l.logger.Debug("unable to tune keepalive parameters for connection", zap.Error(err))
+	}
+}
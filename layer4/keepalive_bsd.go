@@ -0,0 +1,34 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+package layer4
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// setKeepAliveParameters tunes keepalive timing via TCP_KEEPALIVE,
+// TCP_KEEPINTVL and TCP_KEEPCNT. BSD-family stacks expose the idle time as
+// TCP_KEEPALIVE rather than Linux's TCP_KEEPIDLE.
+func setKeepAliveParameters(conn *net.TCPConn, cfg keepAliveConfig) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPALIVE, int(cfg.idle.Seconds())); sockErr != nil {
+			return
+		}
+		if sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, int(cfg.interval.Seconds())); sockErr != nil {
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT, cfg.count)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package layer4
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// setKeepAliveParameters tunes SO_KEEPALIVE timing via TCP_KEEPIDLE,
+// TCP_KEEPINTVL and TCP_KEEPCNT.
+func setKeepAliveParameters(conn *net.TCPConn, cfg keepAliveConfig) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, int(cfg.idle.Seconds())); sockErr != nil {
+			return
+		}
+		if sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, int(cfg.interval.Seconds())); sockErr != nil {
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT, cfg.count)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package layer4
+
+import "net"
+
+// setKeepAliveParameters is a no-op on platforms with no known knob for
+// tuning keepalive idle/interval/count; SetKeepAlive(true) still applies the
+// OS's default keepalive behavior.
+func setKeepAliveParameters(_ *net.TCPConn, _ keepAliveConfig) error {
+	return nil
+}
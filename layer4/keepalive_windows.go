@@ -0,0 +1,45 @@
+//go:build windows
+
+package layer4
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// setKeepAliveParameters tunes keepalive timing via
+// WSAIoctl(SIO_KEEPALIVE_VALS). Windows has no per-connection probe-count
+// knob, so cfg.count is ignored here.
+func setKeepAliveParameters(conn *net.TCPConn, cfg keepAliveConfig) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	params := syscall.TCPKeepalive{
+		OnOff:    1,
+		Time:     uint32(cfg.idle.Milliseconds()),
+		Interval: uint32(cfg.interval.Milliseconds()),
+	}
+
+	var ctlErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ret := uint32(0)
+		ctlErr = syscall.WSAIoctl(
+			syscall.Handle(fd),
+			syscall.SIO_KEEPALIVE_VALS,
+			(*byte)(unsafe.Pointer(&params)),
+			uint32(unsafe.Sizeof(params)),
+			nil,
+			0,
+			&ret,
+			nil,
+			0,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	return ctlErr
+}
@@ -5,15 +5,11 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
 	"github.com/caddyserver/caddy/v2"
 	"go.uber.org/zap"
 	"net"
-	"runtime"
 	"sync"
-	"syscall"
 	"time"
-	"unsafe"
 )
 
 func init() {
@@ -25,10 +21,68 @@ type ListenerWrapper struct {
 	// Routes express composable logic for handling byte streams.
 	Routes RouteList `json:"routes,omitempty"`
 
+	// KeepAliveEnabled controls whether TCP keepalive probes are sent on
+	// accepted connections. Default: true.
+	KeepAliveEnabled *bool `json:"keep_alive_enabled,omitempty"`
+
+	// KeepAliveIdle is how long a connection must sit idle before the first
+	// keepalive probe is sent. Default: 2m.
+	KeepAliveIdle caddy.Duration `json:"keep_alive_idle,omitempty"`
+
+	// KeepAliveInterval is how long to wait between successive keepalive
+	// probes once the connection is considered idle. Default: 15s.
+	KeepAliveInterval caddy.Duration `json:"keep_alive_interval,omitempty"`
+
+	// KeepAliveCount is the number of unacknowledged probes sent before the
+	// connection is considered dead. Not every OS exposes this knob: Windows
+	// ignores it without logging. Tuning that the OS rejects is skipped with
+	// a debug log instead of failing. Default: 9.
+	KeepAliveCount int `json:"keep_alive_count,omitempty"`
+
+	// DrainTimeout is how long Cleanup waits for in-flight connections to
+	// finish on their own before forcibly closing them. Default: 5s.
+	DrainTimeout caddy.Duration `json:"drain_timeout,omitempty"`
+
+	// ConnChanCapacity sets the buffer size of the channel used to hand
+	// hijacked connections back to Caddy's Accept loop. Default: GOMAXPROCS.
+	ConnChanCapacity int `json:"conn_chan_capacity,omitempty"`
+
+	// MaxConcurrentHandlers caps the number of connections concurrently
+	// running route matching/handling. 0 means unlimited.
+	MaxConcurrentHandlers int `json:"max_concurrent_handlers,omitempty"`
+
+	// AcceptBackoff configures the exponential backoff applied after
+	// repeated temporary Accept errors.
+	AcceptBackoff AcceptBackoff `json:"accept_backoff,omitempty"`
+
+	// OverflowPolicy controls what happens to a new connection when
+	// MaxConcurrentHandlers is saturated: "block", "close" (default), or
+	// "reject_with".
+	OverflowPolicy string `json:"overflow_policy,omitempty"`
+
+	// RejectResponse is written to the connection before closing it when
+	// OverflowPolicy is "reject_with".
+	RejectResponse string `json:"reject_response,omitempty"`
+
+	// MatchingTimeout bounds how long compiledRoute.Handle has to match and
+	// dispatch a connection before a handler hijacks it. 0 disables it.
+	MatchingTimeout caddy.Duration `json:"matching_timeout,omitempty"`
+
+	// ReadIdleTimeout is a rolling deadline reset on every successful read;
+	// going idle longer than this closes the connection. 0 disables it.
+	ReadIdleTimeout caddy.Duration `json:"read_idle_timeout,omitempty"`
+
+	// MaxConnectionDuration hard-caps how long a connection may stay open
+	// from accept to close, regardless of activity. 0 disables it.
+	MaxConnectionDuration caddy.Duration `json:"max_connection_duration,omitempty"`
+
 	compiledRoute Handler
 
 	logger *zap.Logger
 	ctx    caddy.Context
+
+	mu        sync.Mutex
+	listeners []*listener
 }
 
 // CaddyModule returns the Caddy module information.
@@ -50,23 +104,91 @@ func (lw *ListenerWrapper) Provision(ctx caddy.Context) error {
 	}
 	lw.compiledRoute = lw.Routes.Compile(listenerHandler{}, lw.logger)
 
+	registerMetrics(ctx.GetMetricsRegistry())
+
 	return nil
 }
 
 func (lw *ListenerWrapper) WrapListener(l net.Listener) net.Listener {
-	// TODO make channel capacity configurable
-	connChan := make(chan net.Conn, runtime.GOMAXPROCS(0))
+	connChan := make(chan net.Conn, lw.connChanCapacity())
+	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	backpressure := lw.backpressureConfig()
+
+	var handlerSem chan struct{}
+	if backpressure.maxConcurrentHandlers > 0 {
+		handlerSem = make(chan struct{}, backpressure.maxConcurrentHandlers)
+	}
+
 	li := &listener{
-		Listener:      l,
-		logger:        lw.logger,
-		compiledRoute: lw.compiledRoute,
-		connChan:      connChan,
-		wg:            new(sync.WaitGroup),
+		Listener:       l,
+		logger:         lw.logger,
+		compiledRoute:  lw.compiledRoute,
+		connChan:       connChan,
+		wg:             new(sync.WaitGroup),
+		keepAlive:      lw.keepAliveConfig(),
+		activeConns:    make(map[net.Conn]struct{}),
+		shutdownCtx:    shutdownCtx,
+		shutdownCancel: shutdownCancel,
+		backpressure:   backpressure,
+		handlerSem:     handlerSem,
+		timeouts:       lw.timeoutConfig(),
 	}
 	go li.loop()
+
+	lw.mu.Lock()
+	lw.listeners = append(lw.listeners, li)
+	lw.mu.Unlock()
+
 	return li
 }
 
+// Cleanup drains and closes all listeners created by this ListenerWrapper. It
+// cancels each listener's connection context so in-flight handlers can
+// observe the shutdown, then waits up to DrainTimeout for them to finish
+// before forcibly closing whatever connections remain, analogous to how
+// net/http.Server.Shutdown coordinates with in-flight requests.
+func (lw *ListenerWrapper) Cleanup() error {
+	lw.mu.Lock()
+	listeners := lw.listeners
+	lw.listeners = nil
+	lw.mu.Unlock()
+
+	drainTimeout := time.Duration(lw.DrainTimeout)
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
+	for _, li := range listeners {
+		li.shutdown(drainTimeout)
+	}
+
+	return nil
+}
+
+// keepAliveConfig resolves the configured keepalive knobs, applying defaults
+// for anything left unset.
+func (lw *ListenerWrapper) keepAliveConfig() keepAliveConfig {
+	cfg := keepAliveConfig{
+		enabled:  true,
+		idle:     defaultKeepAliveIdle,
+		interval: defaultKeepAliveInterval,
+		count:    defaultKeepAliveCount,
+	}
+	if lw.KeepAliveEnabled != nil {
+		cfg.enabled = *lw.KeepAliveEnabled
+	}
+	if lw.KeepAliveIdle > 0 {
+		cfg.idle = time.Duration(lw.KeepAliveIdle)
+	}
+	if lw.KeepAliveInterval > 0 {
+		cfg.interval = time.Duration(lw.KeepAliveInterval)
+	}
+	if lw.KeepAliveCount > 0 {
+		cfg.count = lw.KeepAliveCount
+	}
+	return cfg
+}
+
 type listener struct {
 	net.Listener
 	logger        *zap.Logger
@@ -78,8 +200,35 @@ type listener struct {
 
 	// count running handles
 	wg *sync.WaitGroup
+
+	// keepAlive holds the resolved TCP keepalive tuning applied to accepted connections.
+	keepAlive keepAliveConfig
+
+	// activeConns tracks in-flight connections so shutdown can force-close
+	// whatever is left once the drain timeout elapses.
+	connsMu     sync.Mutex
+	activeConns map[net.Conn]struct{}
+
+	// shutdownCtx is canceled when the listener starts draining, letting
+	// downstream handlers (proxy, tls) observe shutdown and wind down.
+	shutdownCtx    context.Context
+	shutdownCancel context.CancelFunc
+
+	// backpressure holds the resolved accept-backoff and overflow tuning.
+	backpressure backpressureConfig
+
+	// handlerSem bounds the number of concurrently running handle goroutines;
+	// nil means unlimited.
+	handlerSem chan struct{}
+
+	// timeouts holds the resolved matching/idle/max-duration deadlines
+	// applied to each connection.
+	timeouts timeoutConfig
 }
 
+// defaultDrainTimeout is used when DrainTimeout is left unconfigured.
+const defaultDrainTimeout = 5 * time.Second
+
 type tcpConnection interface {
 	SetKeepAlivePeriod(d time.Duration) error
 	SetKeepAlive(bool) error
@@ -87,24 +236,30 @@ type tcpConnection interface {
 
 // loop accept connection from underlying listener and pipe the connection if there are any
 func (l *listener) loop() {
+	backoff := l.backpressure.backoffMin
 	for {
 		conn, err := l.Listener.Accept()
 		if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
-			l.logger.Error("temporary error accepting connection", zap.Error(err))
+			l.logTemporaryAcceptError(err, backoff)
+			time.Sleep(backoff)
+			backoff = l.nextBackoff(backoff)
 			continue
 		}
 		if err != nil {
 			l.err = err
 			break
-		} else {
-			if tconn, ok := conn.(tcpConnection); ok {
-				err = setKeepAliveWorkarround(tconn)
-				if err != nil {
-					l.logger.Warn("unable to set keepalive for new connection:", zap.Error(err))
-				}
-			}
+		}
+		backoff = l.backpressure.backoffMin
+
+		if tconn, ok := conn.(tcpConnection); ok {
+			l.setKeepAlive(tconn)
+		}
+
+		if !l.acquireHandlerSlot(conn) {
+			continue
 		}
 
+		metrics.acceptedTotal.Inc()
 		l.wg.Add(1)
 		go l.handle(conn)
 	}
@@ -122,9 +277,51 @@ func (l *listener) loop() {
 // errHijacked is used when a handler takes over the connection, it's lifetime is not managed by handle
 var errHijacked = errors.New("hijacked connection")
 
+// shutdown stops accepting new connections and waits up to drainTimeout for
+// in-flight handlers to finish before force-closing whatever remains.
+func (l *listener) shutdown(drainTimeout time.Duration) {
+	l.shutdownCancel()
+	_ = l.Listener.Close()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(drainTimeout):
+		l.logger.Warn("drain timeout exceeded, forcing remaining connections closed",
+			zap.Duration("drain_timeout", drainTimeout),
+		)
+		l.connsMu.Lock()
+		for conn := range l.activeConns {
+			conn.Close()
+		}
+		l.connsMu.Unlock()
+	}
+}
+
+func (l *listener) trackConn(conn net.Conn, add bool) {
+	l.connsMu.Lock()
+	defer l.connsMu.Unlock()
+	if add {
+		l.activeConns[conn] = struct{}{}
+	} else {
+		delete(l.activeConns, conn)
+	}
+}
+
 func (l *listener) handle(conn net.Conn) {
+	l.trackConn(conn, true)
+	metrics.activeHandlers.Inc()
+
 	var err error
 	defer func() {
+		metrics.activeHandlers.Dec()
+		l.releaseHandlerSlot()
+		l.trackConn(conn, false)
 		l.wg.Done()
 		if err != errHijacked {
 			conn.Close()
@@ -135,11 +332,29 @@ func (l *listener) handle(conn net.Conn) {
 	buf.Reset()
 	defer bufPool.Put(buf)
 
+	if l.timeouts.readIdle > 0 {
+		conn = &idleTimeoutConn{Conn: conn, idle: l.timeouts.readIdle}
+	}
+
+	stopMaxTimer := l.armMaxConnectionTimer(conn)
+	defer func() { stopMaxTimer(err) }()
+
+	var matchingTimer *time.Timer
+	if l.timeouts.matching > 0 {
+		matchingTimer = time.AfterFunc(l.timeouts.matching, func() {
+			metrics.timeoutsTotal.WithLabelValues("matching").Inc()
+			conn.Close()
+		})
+	}
+
 	cx := WrapConnection(conn, buf, l.logger)
-	cx.Context = context.WithValue(cx.Context, listenerCtxKey, l)
+	cx.Context = context.WithValue(l.shutdownCtx, listenerCtxKey, l)
 
 	start := time.Now()
 	err = l.compiledRoute.Handle(cx)
+	if matchingTimer != nil {
+		matchingTimer.Stop()
+	}
 	duration := time.Since(start)
 	if err != nil && err != errHijacked {
 		l.logger.Error("handling connection", zap.Error(err))
@@ -155,6 +370,7 @@ func (l *listener) handle(conn net.Conn) {
 
 func (l *listener) Accept() (net.Conn, error) {
 	for conn := range l.connChan {
+		metrics.chanDepth.Set(float64(len(l.connChan)))
 		return conn, nil
 	}
 	return nil, l.err
@@ -176,6 +392,7 @@ func (l *listener) pipeConnection(conn *Connection) error {
 	} else {
 		l.connChan <- conn
 	}
+	metrics.chanDepth.Set(float64(len(l.connChan)))
 	return errHijacked
 }
 
@@ -189,39 +406,9 @@ func (tc *tlsConnection) ConnectionState() tls.ConnectionState {
 	return *tc.connState
 }
 
-func setKeepAliveWorkarround(conn tcpConnection) error {
-	rawConn, err := conn.(*net.TCPConn).SyscallConn()
-	if err != nil {
-		return err
-	}
-
-	keepaliveParams := syscall.TCPKeepalive{
-		OnOff:    1,
-		Time:     120000,
-		Interval: 15000,
-	}
-	ret := uint32(0)
-	err = rawConn.Control(func(fd uintptr) {
-		err := syscall.WSAIoctl(
-			syscall.Handle(fd),
-			syscall.SIO_KEEPALIVE_VALS,
-			(*byte)(unsafe.Pointer(&keepaliveParams)),
-			uint32(unsafe.Sizeof(keepaliveParams)),
-			nil,
-			0,
-			&ret,
-			nil,
-			0,
-		)
-		if err != nil {
-			fmt.Println("WSAIoctl error:", err)
-		}
-	})
-	return err
-}
-
 // Interface guards
 var (
 	_ caddy.Module          = (*ListenerWrapper)(nil)
 	_ caddy.ListenerWrapper = (*ListenerWrapper)(nil)
+	_ caddy.CleanerUpper    = (*ListenerWrapper)(nil)
 )
@@ -0,0 +1,48 @@
+package layer4
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the Prometheus collectors shared by every ListenerWrapper
+// instance in the process.
+var metrics = struct {
+	once sync.Once
+
+	acceptedTotal  prometheus.Counter
+	rejectedTotal  prometheus.Counter
+	timeoutsTotal  *prometheus.CounterVec
+	activeHandlers prometheus.Gauge
+	chanDepth      prometheus.Gauge
+}{}
+
+// registerMetrics registers the layer4 listener collectors against registry.
+// It is safe to call multiple times; registration only happens once per
+// process.
+func registerMetrics(registry prometheus.Registerer) {
+	metrics.once.Do(func() {
+		metrics.acceptedTotal = promauto.With(registry).NewCounter(prometheus.CounterOpts{
+			Name: "layer4_accepted_total",
+			Help: "Count of connections accepted by layer4 listeners.",
+		})
+		metrics.rejectedTotal = promauto.With(registry).NewCounter(prometheus.CounterOpts{
+			Name: "layer4_rejected_total",
+			Help: "Count of connections rejected due to backpressure (overflow_policy).",
+		})
+		metrics.timeoutsTotal = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+			Name: "layer4_timeouts_total",
+			Help: "Count of connections closed due to a configured timeout, by reason.",
+		}, []string{"reason"})
+		metrics.activeHandlers = promauto.With(registry).NewGauge(prometheus.GaugeOpts{
+			Name: "layer4_active_handlers",
+			Help: "Number of connections currently being handled (route matching/proxying).",
+		})
+		metrics.chanDepth = promauto.With(registry).NewGauge(prometheus.GaugeOpts{
+			Name: "layer4_chan_depth",
+			Help: "Number of hijacked connections buffered in a listener's accept channel.",
+		})
+	})
+}
@@ -0,0 +1,72 @@
+package layer4
+
+import (
+	"net"
+	"time"
+)
+
+// timeoutConfig holds the resolved per-connection timeout tuning for a
+// listener. All three are opt-in: left at zero, none of them apply, matching
+// the rest of this module's knobs and preserving prior behavior for existing
+// configs that upgrade without setting them.
+type timeoutConfig struct {
+	matching      time.Duration
+	readIdle      time.Duration
+	maxConnection time.Duration
+}
+
+// timeoutConfig resolves the configured timeout knobs, applying defaults for
+// anything left unset.
+func (lw *ListenerWrapper) timeoutConfig() timeoutConfig {
+	var cfg timeoutConfig
+	if lw.MatchingTimeout > 0 {
+		cfg.matching = time.Duration(lw.MatchingTimeout)
+	}
+	if lw.ReadIdleTimeout > 0 {
+		cfg.readIdle = time.Duration(lw.ReadIdleTimeout)
+	}
+	if lw.MaxConnectionDuration > 0 {
+		cfg.maxConnection = time.Duration(lw.MaxConnectionDuration)
+	}
+	return cfg
+}
+
+// idleTimeoutConn wraps net.Conn, resetting a rolling read deadline on every
+// Read so a client that goes idle mid-stream can't tie up the connection
+// indefinitely.
+type idleTimeoutConn struct {
+	net.Conn
+	idle time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	_ = c.SetReadDeadline(time.Now().Add(c.idle))
+	n, err := c.Conn.Read(b)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		metrics.timeoutsTotal.WithLabelValues("read_idle").Inc()
+	}
+	return n, err
+}
+
+// armMaxConnectionTimer starts the max_connection_duration timer for conn, if
+// configured, and returns a stop func to be called with handle's final error
+// once it returns. A hijacked connection lives on past handle returning (it's
+// handed off via connChan to Caddy's own Accept loop), so the timer must stay
+// armed to keep enforcing the hard total-lifetime cap; stop only disarms it
+// when the connection itself is actually closing.
+func (l *listener) armMaxConnectionTimer(conn net.Conn) (stop func(err error)) {
+	if l.timeouts.maxConnection <= 0 {
+		return func(error) {}
+	}
+
+	timer := time.AfterFunc(l.timeouts.maxConnection, func() {
+		metrics.timeoutsTotal.WithLabelValues("max_connection").Inc()
+		conn.Close()
+	})
+
+	return func(err error) {
+		if err != errHijacked {
+			timer.Stop()
+		}
+	}
+}
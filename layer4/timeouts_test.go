@@ -0,0 +1,147 @@
+package layer4
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTimeoutConfigDefaultsToDisabled(t *testing.T) {
+	lw := &ListenerWrapper{}
+	cfg := lw.timeoutConfig()
+
+	if cfg.matching != 0 {
+		t.Errorf("expected matching timeout to default to disabled, got %v", cfg.matching)
+	}
+	if cfg.readIdle != 0 {
+		t.Errorf("expected read idle timeout to default to disabled, got %v", cfg.readIdle)
+	}
+	if cfg.maxConnection != 0 {
+		t.Errorf("expected max connection duration to default to disabled, got %v", cfg.maxConnection)
+	}
+}
+
+func TestTimeoutConfigHonorsExplicitValues(t *testing.T) {
+	lw := &ListenerWrapper{
+		MatchingTimeout:       caddy.Duration(time.Second),
+		ReadIdleTimeout:       caddy.Duration(2 * time.Second),
+		MaxConnectionDuration: caddy.Duration(3 * time.Second),
+	}
+	cfg := lw.timeoutConfig()
+
+	if cfg.matching != time.Second {
+		t.Errorf("expected matching timeout %v, got %v", time.Second, cfg.matching)
+	}
+	if cfg.readIdle != 2*time.Second {
+		t.Errorf("expected read idle timeout %v, got %v", 2*time.Second, cfg.readIdle)
+	}
+	if cfg.maxConnection != 3*time.Second {
+		t.Errorf("expected max connection duration %v, got %v", 3*time.Second, cfg.maxConnection)
+	}
+}
+
+// TestArmMaxConnectionTimerStaysArmedAcrossHijack is a regression test for
+// the bug where the max_connection_duration timer was stopped as soon as
+// handle() returned, even on the hijacked path, so it never bounded a
+// hijacked connection's real lifetime.
+func TestArmMaxConnectionTimerStaysArmedAcrossHijack(t *testing.T) {
+	l := &listener{timeouts: timeoutConfig{maxConnection: 15 * time.Millisecond}}
+	conn := &fakeConn{}
+
+	stop := l.armMaxConnectionTimer(conn)
+	stop(errHijacked) // simulates handle() returning on the hijacked path
+
+	time.Sleep(50 * time.Millisecond)
+	if !conn.isClosed() {
+		t.Fatal("expected the timer to stay armed and close a hijacked connection")
+	}
+}
+
+func TestArmMaxConnectionTimerDisarmsOnNormalReturn(t *testing.T) {
+	l := &listener{timeouts: timeoutConfig{maxConnection: 15 * time.Millisecond}}
+	conn := &fakeConn{}
+
+	stop := l.armMaxConnectionTimer(conn)
+	stop(nil) // simulates handle() returning normally (conn already being closed by its own defer)
+
+	time.Sleep(50 * time.Millisecond)
+	if conn.isClosed() {
+		t.Fatal("expected the timer to be disarmed on a normal (non-hijacked) return")
+	}
+}
+
+func TestArmMaxConnectionTimerFiresWhenNeverStopped(t *testing.T) {
+	l := &listener{timeouts: timeoutConfig{maxConnection: 15 * time.Millisecond}}
+	conn := &fakeConn{}
+
+	l.armMaxConnectionTimer(conn)
+
+	time.Sleep(50 * time.Millisecond)
+	if !conn.isClosed() {
+		t.Fatal("expected the max_connection_duration timer to close the connection once it elapses")
+	}
+}
+
+func TestArmMaxConnectionTimerNoopWhenDisabled(t *testing.T) {
+	l := &listener{}
+	conn := &fakeConn{}
+
+	stop := l.armMaxConnectionTimer(conn)
+	stop(errHijacked)
+
+	time.Sleep(20 * time.Millisecond)
+	if conn.isClosed() {
+		t.Fatal("expected no timer to be armed when max_connection_duration is disabled")
+	}
+}
+
+func TestIdleTimeoutConnResetsReadDeadlineOnEveryRead(t *testing.T) {
+	conn := &fakeConn{}
+	c := &idleTimeoutConn{Conn: conn, idle: time.Second}
+
+	_, _ = c.Read(make([]byte, 1))
+
+	conn.mu.Lock()
+	deadline := conn.readDeadline
+	conn.mu.Unlock()
+
+	if deadline.IsZero() {
+		t.Fatal("expected Read to set a read deadline on the underlying conn")
+	}
+	if time.Until(deadline) <= 0 {
+		t.Fatal("expected the read deadline to be in the future")
+	}
+}
+
+// TestIdleTimeoutConnCountsReadIdleTimeout is a regression test: unlike
+// matching_timeout and max_connection_duration, a fired read_idle deadline
+// used to go uncounted in layer4_timeouts_total.
+func TestIdleTimeoutConnCountsReadIdleTimeout(t *testing.T) {
+	conn := &fakeConn{readErr: fakeTimeoutError{}}
+	c := &idleTimeoutConn{Conn: conn, idle: time.Millisecond}
+
+	before := testutil.ToFloat64(metrics.timeoutsTotal.WithLabelValues("read_idle"))
+	_, _ = c.Read(make([]byte, 1))
+	after := testutil.ToFloat64(metrics.timeoutsTotal.WithLabelValues("read_idle"))
+
+	if after != before+1 {
+		t.Fatalf("expected read_idle timeout counter to increment by 1, before=%v after=%v", before, after)
+	}
+}
+
+func TestIdleTimeoutConnDoesNotCountNonTimeoutReadErrors(t *testing.T) {
+	conn := &fakeConn{readErr: io.EOF}
+	c := &idleTimeoutConn{Conn: conn, idle: time.Second}
+
+	before := testutil.ToFloat64(metrics.timeoutsTotal.WithLabelValues("read_idle"))
+	_, _ = c.Read(make([]byte, 1))
+	after := testutil.ToFloat64(metrics.timeoutsTotal.WithLabelValues("read_idle"))
+
+	if after != before {
+		t.Fatalf("expected read_idle timeout counter not to change on a non-timeout error, before=%v after=%v", before, after)
+	}
+}
+
@@ -0,0 +1,13 @@
+// Package l4auth provides a pluggable authentication matcher and handler for
+// layer4 routes, giving L4 routes a real authz story beyond IP matching.
+package l4auth
+
+// Backend authenticates an identity/secret pair (e.g. a username and
+// password, or a certificate CN with no secret) against a credential store.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Authenticate reports whether identity/secret are valid. A backend that
+	// doesn't use a secret (e.g. one driven purely by a TLS client cert CN)
+	// should ignore it and authenticate on identity alone.
+	Authenticate(identity, secret string) (bool, error)
+}
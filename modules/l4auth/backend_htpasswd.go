@@ -0,0 +1,118 @@
+package l4auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	caddy.RegisterModule(new(HtpasswdBackend))
+}
+
+// HtpasswdBackend authenticates against a bcrypt htpasswd file, reloading it
+// whenever its mtime advances past the last loaded one, so credentials can be
+// rotated without a config reload or restart. A file whose mtime moves
+// backwards is not reloaded.
+type HtpasswdBackend struct {
+	// Path is the htpasswd file to read. Only the bcrypt ($2y$/$2a$/$2b$)
+	// hash format is supported.
+	Path string `json:"path,omitempty"`
+
+	mu      sync.RWMutex
+	users   map[string]string
+	modTime time.Time
+}
+
+// CaddyModule returns the Caddy module information.
+func (*HtpasswdBackend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "layer4.auth.backends.htpasswd",
+		New: func() caddy.Module { return new(HtpasswdBackend) },
+	}
+}
+
+// Provision loads the htpasswd file for the first time.
+func (b *HtpasswdBackend) Provision(_ caddy.Context) error {
+	return b.reloadIfChanged()
+}
+
+// Authenticate implements Backend.
+func (b *HtpasswdBackend) Authenticate(identity, secret string) (bool, error) {
+	if err := b.reloadIfChanged(); err != nil {
+		return false, err
+	}
+
+	b.mu.RLock()
+	hash, ok := b.users[identity]
+	b.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret))
+	return err == nil, nil
+}
+
+// reloadIfChanged re-reads Path when its mtime has advanced since the last load.
+func (b *HtpasswdBackend) reloadIfChanged() error {
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return fmt.Errorf("stat htpasswd file: %w", err)
+	}
+
+	b.mu.RLock()
+	unchanged := !info.ModTime().After(b.modTime)
+	b.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	users, err := parseHtpasswd(b.Path)
+	if err != nil {
+		return fmt.Errorf("parse htpasswd file: %w", err)
+	}
+
+	b.mu.Lock()
+	b.users = users
+	b.modTime = info.ModTime()
+	b.mu.Unlock()
+
+	return nil
+}
+
+func parseHtpasswd(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		users[user] = hash
+	}
+	return users, scanner.Err()
+}
+
+// Interface guards
+var (
+	_ caddy.Module      = (*HtpasswdBackend)(nil)
+	_ caddy.Provisioner = (*HtpasswdBackend)(nil)
+	_ Backend           = (*HtpasswdBackend)(nil)
+)
@@ -0,0 +1,77 @@
+package l4auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeHtpasswd(t *testing.T, path, user, password string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(user+":"+string(hash)+"\n"), 0o600); err != nil {
+		t.Fatalf("writing htpasswd file: %v", err)
+	}
+}
+
+func TestHtpasswdBackendAuthenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	writeHtpasswd(t, path, "alice", "s3cret")
+
+	b := &HtpasswdBackend{Path: path}
+	if err := b.Provision(caddy.Context{}); err != nil {
+		t.Fatalf("unexpected error from Provision: %v", err)
+	}
+
+	if ok, err := b.Authenticate("alice", "s3cret"); err != nil || !ok {
+		t.Fatalf("expected correct credentials to authenticate, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := b.Authenticate("alice", "wrong"); err != nil || ok {
+		t.Fatalf("expected a wrong password to fail authentication, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := b.Authenticate("bob", "s3cret"); err != nil || ok {
+		t.Fatalf("expected an unknown user to fail authentication, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHtpasswdBackendReloadsOnMtimeChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	writeHtpasswd(t, path, "alice", "first")
+
+	b := &HtpasswdBackend{Path: path}
+	if err := b.Provision(caddy.Context{}); err != nil {
+		t.Fatalf("unexpected error from Provision: %v", err)
+	}
+	if ok, _ := b.Authenticate("alice", "first"); !ok {
+		t.Fatal("expected the initial password to authenticate")
+	}
+
+	writeHtpasswd(t, path, "alice", "second")
+	// reloadIfChanged only reloads when mtime has *advanced*; force that on
+	// filesystems with coarse mtime resolution.
+	future := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	if ok, err := b.Authenticate("alice", "second"); err != nil || !ok {
+		t.Fatalf("expected the updated password to authenticate after reload, got ok=%v err=%v", ok, err)
+	}
+	if ok, _ := b.Authenticate("alice", "first"); ok {
+		t.Fatal("expected the stale password to stop authenticating after reload")
+	}
+}
+
+func TestHtpasswdBackendProvisionMissingFile(t *testing.T) {
+	b := &HtpasswdBackend{Path: filepath.Join(t.TempDir(), "missing")}
+	if err := b.Provision(caddy.Context{}); err == nil {
+		t.Fatal("expected Provision to fail when the htpasswd file doesn't exist")
+	}
+}
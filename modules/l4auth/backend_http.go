@@ -0,0 +1,80 @@
+package l4auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(new(HTTPBackend))
+}
+
+// HTTPBackend delegates authentication decisions to an external HTTP
+// endpoint, POSTing the identity/secret pair as JSON and treating any 2xx
+// response as authenticated.
+type HTTPBackend struct {
+	// URL is the callout endpoint to POST credentials to.
+	URL string `json:"url,omitempty"`
+
+	// Timeout bounds the callout request. Default: 5s.
+	Timeout caddy.Duration `json:"timeout,omitempty"`
+
+	client *http.Client
+}
+
+// CaddyModule returns the Caddy module information.
+func (*HTTPBackend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "layer4.auth.backends.http",
+		New: func() caddy.Module { return new(HTTPBackend) },
+	}
+}
+
+// Provision sets up the backend's HTTP client.
+func (b *HTTPBackend) Provision(_ caddy.Context) error {
+	if _, err := url.ParseRequestURI(b.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	timeout := 5 * time.Second
+	if b.Timeout > 0 {
+		timeout = time.Duration(b.Timeout)
+	}
+	b.client = &http.Client{Timeout: timeout}
+
+	return nil
+}
+
+type authCalloutRequest struct {
+	Identity string `json:"identity"`
+	Secret   string `json:"secret"`
+}
+
+// Authenticate implements Backend.
+func (b *HTTPBackend) Authenticate(identity, secret string) (bool, error) {
+	body, err := json.Marshal(authCalloutRequest{Identity: identity, Secret: secret})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := b.client.Post(b.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300, nil
+}
+
+// Interface guards
+var (
+	_ caddy.Module      = (*HTTPBackend)(nil)
+	_ caddy.Provisioner = (*HTTPBackend)(nil)
+	_ Backend           = (*HTTPBackend)(nil)
+)
@@ -0,0 +1,74 @@
+package l4auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func httpBackendHandler(t *testing.T, status int, wantIdentity, wantSecret string) http.HandlerFunc {
+	t.Helper()
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req authCalloutRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding callout body: %v", err)
+		}
+		if req.Identity != wantIdentity || req.Secret != wantSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func TestHTTPBackendAuthenticateSuccess(t *testing.T) {
+	srv := httptest.NewServer(httpBackendHandler(t, http.StatusOK, "alice", "s3cret"))
+	defer srv.Close()
+
+	b := &HTTPBackend{URL: srv.URL}
+	if err := b.Provision(caddy.Context{}); err != nil {
+		t.Fatalf("unexpected error from Provision: %v", err)
+	}
+
+	if ok, err := b.Authenticate("alice", "s3cret"); err != nil || !ok {
+		t.Fatalf("expected a 2xx response to authenticate, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHTTPBackendAuthenticateNon2xxFails(t *testing.T) {
+	srv := httptest.NewServer(httpBackendHandler(t, http.StatusForbidden, "alice", "s3cret"))
+	defer srv.Close()
+
+	b := &HTTPBackend{URL: srv.URL}
+	if err := b.Provision(caddy.Context{}); err != nil {
+		t.Fatalf("unexpected error from Provision: %v", err)
+	}
+
+	if ok, err := b.Authenticate("alice", "s3cret"); err != nil || ok {
+		t.Fatalf("expected a non-2xx response to fail authentication, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHTTPBackendAuthenticateWrongCredentialsFails(t *testing.T) {
+	srv := httptest.NewServer(httpBackendHandler(t, http.StatusOK, "alice", "s3cret"))
+	defer srv.Close()
+
+	b := &HTTPBackend{URL: srv.URL}
+	if err := b.Provision(caddy.Context{}); err != nil {
+		t.Fatalf("unexpected error from Provision: %v", err)
+	}
+
+	if ok, err := b.Authenticate("alice", "wrong"); err != nil || ok {
+		t.Fatalf("expected wrong credentials to fail authentication, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHTTPBackendProvisionRejectsInvalidURL(t *testing.T) {
+	b := &HTTPBackend{URL: "not-a-url"}
+	if err := b.Provision(caddy.Context{}); err == nil {
+		t.Fatal("expected Provision to reject an invalid URL")
+	}
+}
@@ -0,0 +1,43 @@
+package l4auth
+
+import (
+	"crypto/subtle"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(StaticBackend{})
+}
+
+// StaticBackend authenticates against a fixed, config-supplied user/pass
+// list. It's the simplest backend and is mainly useful for small,
+// rarely-changing deployments; prefer HtpasswdBackend for anything that
+// needs to be rotated without a config reload.
+type StaticBackend struct {
+	// Users maps username to plaintext password.
+	Users map[string]string `json:"users,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (StaticBackend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "layer4.auth.backends.static",
+		New: func() caddy.Module { return new(StaticBackend) },
+	}
+}
+
+// Authenticate implements Backend.
+func (b StaticBackend) Authenticate(identity, secret string) (bool, error) {
+	want, ok := b.Users[identity]
+	if !ok {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(want), []byte(secret)) == 1, nil
+}
+
+// Interface guards
+var (
+	_ caddy.Module = (*StaticBackend)(nil)
+	_ Backend      = (*StaticBackend)(nil)
+)
@@ -0,0 +1,39 @@
+package l4auth
+
+import "testing"
+
+func TestStaticBackendAuthenticateSuccess(t *testing.T) {
+	b := StaticBackend{Users: map[string]string{"alice": "s3cret"}}
+
+	ok, err := b.Authenticate("alice", "s3cret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected matching identity/secret to authenticate")
+	}
+}
+
+func TestStaticBackendAuthenticateWrongSecret(t *testing.T) {
+	b := StaticBackend{Users: map[string]string{"alice": "s3cret"}}
+
+	ok, err := b.Authenticate("alice", "wrong")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Fatal("expected a wrong secret to fail authentication")
+	}
+}
+
+func TestStaticBackendAuthenticateUnknownUser(t *testing.T) {
+	b := StaticBackend{Users: map[string]string{"alice": "s3cret"}}
+
+	ok, err := b.Authenticate("bob", "anything")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Fatal("expected an unknown user to fail authentication")
+	}
+}
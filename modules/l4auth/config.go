@@ -0,0 +1,90 @@
+package l4auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Gr33nbl00d/caddy-l4/layer4"
+	"github.com/caddyserver/caddy/v2"
+)
+
+// authConfig holds the backend and identity/secret extraction settings
+// shared by MatchAuth and AuthHandler.
+type authConfig struct {
+	// BackendRaw is the authentication backend to check credentials against.
+	BackendRaw json.RawMessage `json:"backend,omitempty" caddy:"namespace=layer4.auth.backends inline_key=backend"`
+
+	// IdentityVar is the layer4 connection variable holding the identity to
+	// authenticate, e.g. a TLS client cert CN/SAN, an SSH username, or a var
+	// set by an earlier handler that parsed a length-prefixed handshake.
+	IdentityVar string `json:"identity_var,omitempty"`
+
+	// SecretVar is the layer4 connection variable holding the secret to
+	// authenticate with, if any. Leave empty for identity-only backends such
+	// as TLS client certificates.
+	SecretVar string `json:"secret_var,omitempty"`
+
+	backend Backend
+}
+
+// provision resolves BackendRaw into a concrete Backend.
+func (c *authConfig) provision(ctx caddy.Context) error {
+	if c.IdentityVar == "" {
+		return fmt.Errorf("identity_var is required")
+	}
+	if c.BackendRaw == nil {
+		return fmt.Errorf("backend is required")
+	}
+
+	mod, err := ctx.LoadModule(c, "BackendRaw")
+	if err != nil {
+		return fmt.Errorf("loading auth backend module: %w", err)
+	}
+	backend, ok := mod.(Backend)
+	if !ok {
+		return fmt.Errorf("module %T is not a l4auth.Backend", mod)
+	}
+	c.backend = backend
+
+	return nil
+}
+
+// authenticate pulls the configured identity/secret vars off cx and checks
+// them against the backend. The returned reason is one of
+// "missing_identity", "invalid_credentials", "backend_error" (returned
+// together with the backend's error) or "" (authenticated).
+func (c *authConfig) authenticate(cx *layer4.Connection) (ok bool, reason string, err error) {
+	identity, _ := cx.GetVar(c.IdentityVar).(string)
+	if identity == "" {
+		return false, "missing_identity", nil
+	}
+
+	var secret string
+	if c.SecretVar != "" {
+		secret, _ = cx.GetVar(c.SecretVar).(string)
+	}
+
+	authenticated, err := c.backend.Authenticate(identity, secret)
+	if err != nil {
+		return false, "backend_error", err
+	}
+	if !authenticated {
+		return false, "invalid_credentials", nil
+	}
+	return true, "", nil
+}
+
+// backendName returns the short module name of the configured backend
+// (e.g. "static", "htpasswd"), used as a metrics label.
+func (c *authConfig) backendName() string {
+	mod, ok := c.backend.(caddy.Module)
+	if !ok {
+		return "unknown"
+	}
+	id := string(mod.CaddyModule().ID)
+	if idx := strings.LastIndex(id, "."); idx >= 0 {
+		return id[idx+1:]
+	}
+	return id
+}
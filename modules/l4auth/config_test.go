@@ -0,0 +1,44 @@
+package l4auth
+
+import (
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// authConfig.authenticate and .provision both take/construct a
+// *layer4.Connection / caddy.Context wired into a running app, which this
+// backend-only test file can't stand up in isolation; backendName is the
+// piece of authConfig reachable without one.
+
+type fakeModuleBackend struct {
+	ok bool
+}
+
+func (f fakeModuleBackend) Authenticate(identity, secret string) (bool, error) {
+	return f.ok, nil
+}
+
+func (fakeModuleBackend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{ID: "layer4.auth.backends.fake"}
+}
+
+type fakeBareBackend struct{}
+
+func (fakeBareBackend) Authenticate(identity, secret string) (bool, error) {
+	return false, nil
+}
+
+func TestAuthConfigBackendNameUsesLastModuleIDSegment(t *testing.T) {
+	c := authConfig{backend: fakeModuleBackend{ok: true}}
+	if name := c.backendName(); name != "fake" {
+		t.Fatalf("expected backend name %q, got %q", "fake", name)
+	}
+}
+
+func TestAuthConfigBackendNameFallsBackWhenNotAModule(t *testing.T) {
+	c := authConfig{backend: fakeBareBackend{}}
+	if name := c.backendName(); name != "unknown" {
+		t.Fatalf("expected fallback backend name %q, got %q", "unknown", name)
+	}
+}
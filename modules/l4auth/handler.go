@@ -0,0 +1,65 @@
+package l4auth
+
+import (
+	"errors"
+
+	"github.com/Gr33nbl00d/caddy-l4/layer4"
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(new(AuthHandler))
+}
+
+// errAuthFailed is returned when a connection fails to authenticate; the
+// listener closes the connection same as for any other handler error.
+var errAuthFailed = errors.New("l4auth: authentication failed")
+
+// AuthHandler rejects or terminates connections that fail to authenticate
+// against the configured backend, recording layer4_auth_denied_total.
+// Connections that authenticate successfully are passed to next unchanged.
+type AuthHandler struct {
+	authConfig
+
+	// RejectResponse is written to the connection before closing it when
+	// authentication fails, letting a route send a protocol-appropriate
+	// rejection (e.g. a plaintext banner or error frame).
+	RejectResponse string `json:"reject_response,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (*AuthHandler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "layer4.handlers.auth",
+		New: func() caddy.Module { return new(AuthHandler) },
+	}
+}
+
+// Provision sets up the handler's backend.
+func (h *AuthHandler) Provision(ctx caddy.Context) error {
+	registerMetrics(ctx.GetMetricsRegistry())
+	return h.authConfig.provision(ctx)
+}
+
+// Handle implements layer4.NextHandler.
+func (h *AuthHandler) Handle(cx *layer4.Connection, next layer4.Handler) error {
+	ok, reason, err := h.authenticate(cx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		deniedTotal.WithLabelValues(h.backendName(), reason).Inc()
+		if h.RejectResponse != "" {
+			_, _ = cx.Write([]byte(h.RejectResponse))
+		}
+		return errAuthFailed
+	}
+	return next.Handle(cx)
+}
+
+// Interface guards
+var (
+	_ caddy.Module       = (*AuthHandler)(nil)
+	_ caddy.Provisioner  = (*AuthHandler)(nil)
+	_ layer4.NextHandler = (*AuthHandler)(nil)
+)
@@ -0,0 +1,49 @@
+package l4auth
+
+import (
+	"github.com/Gr33nbl00d/caddy-l4/layer4"
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(new(MatchAuth))
+}
+
+// MatchAuth matches connections whose identity/secret authenticate
+// successfully against the configured backend.
+type MatchAuth struct {
+	authConfig
+}
+
+// CaddyModule returns the Caddy module information.
+func (*MatchAuth) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "layer4.matchers.auth",
+		New: func() caddy.Module { return new(MatchAuth) },
+	}
+}
+
+// Provision sets up the matcher's backend.
+func (m *MatchAuth) Provision(ctx caddy.Context) error {
+	registerMetrics(ctx.GetMetricsRegistry())
+	return m.authConfig.provision(ctx)
+}
+
+// Match implements layer4.ConnMatcher.
+func (m *MatchAuth) Match(cx *layer4.Connection) (bool, error) {
+	ok, reason, err := m.authenticate(cx)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		deniedTotal.WithLabelValues(m.backendName(), reason).Inc()
+	}
+	return ok, nil
+}
+
+// Interface guards
+var (
+	_ caddy.Module       = (*MatchAuth)(nil)
+	_ caddy.Provisioner  = (*MatchAuth)(nil)
+	_ layer4.ConnMatcher = (*MatchAuth)(nil)
+)
@@ -0,0 +1,24 @@
+package l4auth
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	deniedOnce  sync.Once
+	deniedTotal *prometheus.CounterVec
+)
+
+// registerMetrics registers the l4auth collectors against registry. Safe to
+// call multiple times; registration only happens once per process.
+func registerMetrics(registry prometheus.Registerer) {
+	deniedOnce.Do(func() {
+		deniedTotal = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+			Name: "layer4_auth_denied_total",
+			Help: "Count of connections denied by the layer4 auth matcher/handler, by backend and reason.",
+		}, []string{"backend", "reason"})
+	})
+}
@@ -0,0 +1,262 @@
+// Package l4proxy implements the layer4.handlers.proxy module. Its
+// connection pool is opt-in: Proxy builds one Pool per configuration when
+// ConnectionPool is set and calls Get/Put around its dial logic instead of
+// dialing a fresh upstream connection per accepted client; see proxy.go.
+package l4proxy
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+)
+
+// PoolKey identifies a reusable set of idle upstream connections.
+type PoolKey struct {
+	Network        string
+	Address        string
+	TLSFingerprint string // empty for plaintext upstreams
+}
+
+// KeyFor builds a PoolKey for a dial to network/address, optionally fingerprinting
+// tlsConfig so upstreams dialed with different TLS settings don't share a pool.
+func KeyFor(network, address string, tlsConfig *tls.Config) PoolKey {
+	var fingerprint string
+	if tlsConfig != nil {
+		fingerprint = tlsConfigFingerprint(tlsConfig)
+	}
+	return PoolKey{Network: network, Address: address, TLSFingerprint: fingerprint}
+}
+
+func tlsConfigFingerprint(cfg *tls.Config) string {
+	h := sha256.New()
+	h.Write([]byte(cfg.ServerName))
+	for _, proto := range cfg.NextProtos {
+		h.Write([]byte(proto))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idleConn is a pooled upstream connection, tracked in both its per-key list
+// and the global LRU list used for MaxIdleConns eviction.
+type idleConn struct {
+	conn    net.Conn
+	key     PoolKey
+	addedAt time.Time
+	keyElem *list.Element
+	lruElem *list.Element
+}
+
+// Pool maintains idle upstream connections keyed by (network, address,
+// tls-fingerprint), mirroring net/http.Transport's idleConn/idleLRU/
+// idleConnTimeout design.
+type Pool struct {
+	// MaxIdleConns caps the total number of idle connections held across all
+	// keys. 0 means unlimited.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost caps idle connections per PoolKey.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes an idle connection that hasn't been reused
+	// within this long. 0 disables the timeout and the reaper goroutine.
+	IdleConnTimeout time.Duration
+
+	mu       sync.Mutex
+	idle     map[PoolKey]*list.List
+	idleLRU  *list.List
+	idleLen  int
+	closed   bool
+	stopReap chan struct{}
+}
+
+// NewPool builds a Pool with the given limits. maxIdleConnsPerHost defaults
+// to 2 (matching net/http.Transport's default) when left at 0.
+func NewPool(maxIdleConns, maxIdleConnsPerHost int, idleConnTimeout time.Duration) *Pool {
+	if maxIdleConnsPerHost <= 0 {
+		maxIdleConnsPerHost = 2
+	}
+
+	p := &Pool{
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+		idle:                make(map[PoolKey]*list.List),
+		idleLRU:             list.New(),
+		stopReap:            make(chan struct{}),
+	}
+
+	if idleConnTimeout > 0 {
+		go p.reap()
+	}
+
+	return p
+}
+
+// Get returns a pooled, still-live connection for key, if one is available.
+// Connections are validated with a non-blocking read before being handed
+// back, so a peer that closed the connection while it sat idle doesn't get
+// silently reused.
+func (p *Pool) Get(key PoolKey) (net.Conn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	l, ok := p.idle[key]
+	if !ok {
+		return nil, false
+	}
+
+	for l.Len() > 0 {
+		elem := l.Front()
+		ic := elem.Value.(*idleConn)
+		p.removeLocked(ic)
+
+		if validateIdleConn(ic.conn) {
+			return ic.conn, true
+		}
+		ic.conn.Close()
+	}
+
+	return nil, false
+}
+
+// Put returns conn to the pool for reuse under key. It reports whether conn
+// was accepted; if false, the caller is still responsible for closing conn
+// (the pool is closed, or already holds MaxIdleConnsPerHost connections for
+// this key).
+func (p *Pool) Put(key PoolKey, conn net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return false
+	}
+
+	l, ok := p.idle[key]
+	if !ok {
+		l = list.New()
+		p.idle[key] = l
+	}
+	if l.Len() >= p.MaxIdleConnsPerHost {
+		return false
+	}
+	if p.MaxIdleConns > 0 && p.idleLen >= p.MaxIdleConns {
+		p.evictOldestLocked()
+	}
+
+	ic := &idleConn{conn: conn, key: key, addedAt: time.Now()}
+	ic.keyElem = l.PushBack(ic)
+	ic.lruElem = p.idleLRU.PushBack(ic)
+	p.idleLen++
+
+	return true
+}
+
+// Close closes every pooled connection and stops the reaper. Once closed,
+// Put always reports false, so the pool can be safely wired into a
+// listener's graceful-shutdown path without a race against in-flight Puts.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+
+	var conns []net.Conn
+	for elem := p.idleLRU.Front(); elem != nil; elem = elem.Next() {
+		conns = append(conns, elem.Value.(*idleConn).conn)
+	}
+	p.idle = make(map[PoolKey]*list.List)
+	p.idleLRU = list.New()
+	p.idleLen = 0
+	p.mu.Unlock()
+
+	if p.IdleConnTimeout > 0 {
+		close(p.stopReap)
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	return nil
+}
+
+// removeLocked detaches ic from both the per-key and LRU lists. Callers must
+// hold p.mu.
+func (p *Pool) removeLocked(ic *idleConn) {
+	if l, ok := p.idle[ic.key]; ok {
+		l.Remove(ic.keyElem)
+	}
+	p.idleLRU.Remove(ic.lruElem)
+	p.idleLen--
+}
+
+// evictOldestLocked closes and removes the least-recently-added idle
+// connection across all keys. Callers must hold p.mu.
+func (p *Pool) evictOldestLocked() {
+	elem := p.idleLRU.Front()
+	if elem == nil {
+		return
+	}
+	ic := elem.Value.(*idleConn)
+	p.removeLocked(ic)
+	ic.conn.Close()
+}
+
+// reap periodically closes idle connections that have exceeded IdleConnTimeout.
+func (p *Pool) reap() {
+	ticker := time.NewTicker(p.IdleConnTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.reapOnce()
+		case <-p.stopReap:
+			return
+		}
+	}
+}
+
+func (p *Pool) reapOnce() {
+	cutoff := time.Now().Add(-p.IdleConnTimeout)
+
+	p.mu.Lock()
+	var expired []*idleConn
+	for elem := p.idleLRU.Front(); elem != nil; {
+		ic := elem.Value.(*idleConn)
+		if ic.addedAt.After(cutoff) {
+			break // idleLRU is ordered oldest-first; nothing older remains
+		}
+		next := elem.Next()
+		p.removeLocked(ic)
+		expired = append(expired, ic)
+		elem = next
+	}
+	p.mu.Unlock()
+
+	for _, ic := range expired {
+		ic.conn.Close()
+	}
+}
+
+// validateIdleConn checks whether a pooled connection is still usable by
+// attempting a non-blocking read: a successful zero-byte read with a timeout
+// error means no data is waiting and the peer hasn't closed the connection;
+// anything else (unexpected data, EOF, reset) means it can't be reused.
+func validateIdleConn(conn net.Conn) bool {
+	if err := conn.SetReadDeadline(time.Now()); err != nil {
+		return false
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+	n, err := conn.Read(b[:])
+	if n > 0 || err == nil {
+		return false
+	}
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
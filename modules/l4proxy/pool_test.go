@@ -0,0 +1,186 @@
+package l4proxy
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolPutGetRoundTrip(t *testing.T) {
+	p := NewPool(0, 2, 0)
+	defer p.Close()
+
+	client, upstream := net.Pipe()
+	defer upstream.Close()
+
+	key := PoolKey{Network: "tcp", Address: "example:1"}
+	if !p.Put(key, client) {
+		t.Fatal("expected Put to accept the connection")
+	}
+
+	got, ok := p.Get(key)
+	if !ok {
+		t.Fatal("expected Get to return the pooled connection")
+	}
+	if got != client {
+		t.Fatal("expected Get to return the exact connection that was pooled")
+	}
+}
+
+func TestPoolGetOnEmptyKeyReportsNotFound(t *testing.T) {
+	p := NewPool(0, 2, 0)
+	defer p.Close()
+
+	if _, ok := p.Get(PoolKey{Network: "tcp", Address: "nothing"}); ok {
+		t.Fatal("expected Get to report false for a key with nothing pooled")
+	}
+}
+
+func TestPoolPerHostCapRejectsExtra(t *testing.T) {
+	p := NewPool(0, 1, 0)
+	defer p.Close()
+
+	key := PoolKey{Network: "tcp", Address: "example:1"}
+
+	a, aPeer := net.Pipe()
+	defer aPeer.Close()
+	b, bPeer := net.Pipe()
+	defer bPeer.Close()
+	defer b.Close()
+
+	if !p.Put(key, a) {
+		t.Fatal("expected the first Put to be accepted")
+	}
+	if p.Put(key, b) {
+		t.Fatal("expected the second Put to be rejected once MaxIdleConnsPerHost is reached")
+	}
+}
+
+func TestPoolGlobalCapEvictsOldest(t *testing.T) {
+	p := NewPool(1, 5, 0)
+	defer p.Close()
+
+	k1 := PoolKey{Network: "tcp", Address: "a"}
+	k2 := PoolKey{Network: "tcp", Address: "b"}
+
+	c1, c1Peer := net.Pipe()
+	defer c1Peer.Close()
+	c2, c2Peer := net.Pipe()
+	defer c2Peer.Close()
+
+	if !p.Put(k1, c1) {
+		t.Fatal("expected the first Put to be accepted")
+	}
+	if !p.Put(k2, c2) {
+		t.Fatal("expected the second Put to be accepted, evicting the first")
+	}
+
+	if _, ok := p.Get(k1); ok {
+		t.Fatal("expected the oldest pooled connection to have been evicted under MaxIdleConns=1")
+	}
+	if _, ok := p.Get(k2); !ok {
+		t.Fatal("expected the newest pooled connection to still be available")
+	}
+}
+
+func TestPoolReapClosesExpiredIdleConns(t *testing.T) {
+	p := NewPool(0, 5, 20*time.Millisecond)
+	defer p.Close()
+
+	key := PoolKey{Network: "tcp", Address: "a"}
+	client, upstream := net.Pipe()
+	defer upstream.Close()
+
+	if !p.Put(key, client) {
+		t.Fatal("expected Put to accept the connection")
+	}
+
+	time.Sleep(80 * time.Millisecond)
+
+	if _, ok := p.Get(key); ok {
+		t.Fatal("expected the idle connection to have been reaped after IdleConnTimeout elapsed")
+	}
+	if _, err := client.Write([]byte("x")); err == nil {
+		t.Fatal("expected the reaped connection to have been closed")
+	}
+}
+
+func TestPoolCloseClosesPooledConnsAndRejectsFuturePuts(t *testing.T) {
+	p := NewPool(0, 5, 0)
+	key := PoolKey{Network: "tcp", Address: "a"}
+
+	client, upstream := net.Pipe()
+	defer upstream.Close()
+	if !p.Put(key, client) {
+		t.Fatal("expected Put to accept the connection")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("unexpected error from Close: %v", err)
+	}
+
+	if _, err := client.Write([]byte("x")); err == nil {
+		t.Fatal("expected the pooled connection to be closed by Pool.Close")
+	}
+
+	other, otherPeer := net.Pipe()
+	defer otherPeer.Close()
+	defer other.Close()
+	if p.Put(key, other) {
+		t.Fatal("expected Put to reject connections once the pool is closed")
+	}
+
+	// Close must itself be idempotent since a listener's graceful-shutdown
+	// path may race a config reload into calling it twice.
+	if err := p.Close(); err != nil {
+		t.Fatalf("unexpected error from a second Close: %v", err)
+	}
+}
+
+func TestValidateIdleConnDetectsPeerClosed(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	peer.Close()
+
+	if validateIdleConn(conn) {
+		t.Fatal("expected validateIdleConn to report a peer-closed connection as invalid")
+	}
+}
+
+func TestValidateIdleConnAcceptsLiveConnection(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	if !validateIdleConn(conn) {
+		t.Fatal("expected validateIdleConn to report a live, idle connection as valid")
+	}
+}
+
+func TestPoolConcurrentPutAndGetDoNotRace(t *testing.T) {
+	p := NewPool(10, 10, 0)
+	defer p.Close()
+
+	key := PoolKey{Network: "tcp", Address: "a"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, upstream := net.Pipe()
+			defer upstream.Close()
+			if !p.Put(key, client) {
+				client.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for {
+		if _, ok := p.Get(key); !ok {
+			break
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package l4proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Gr33nbl00d/caddy-l4/layer4"
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(new(Proxy))
+}
+
+// defaultDialTimeout bounds a fresh upstream dial when none is pooled.
+const defaultDialTimeout = 10 * time.Second
+
+// PoolConfig opts a Proxy into reusing idle upstream connections instead of
+// dialing a fresh one per accepted client.
+type PoolConfig struct {
+	// MaxIdleConns caps the total number of idle upstream connections held
+	// across all upstreams. 0 means unlimited.
+	MaxIdleConns int `json:"max_idle_conns,omitempty"`
+
+	// MaxIdleConnsPerHost caps idle connections per upstream. Default: 2.
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
+
+	// IdleConnTimeout closes a pooled connection that hasn't been reused
+	// within this long. 0 disables the timeout.
+	IdleConnTimeout caddy.Duration `json:"idle_conn_timeout,omitempty"`
+}
+
+// Proxy dials an upstream and relays bytes between it and the accepted
+// connection, optionally reusing idle upstream connections via ConnectionPool.
+type Proxy struct {
+	// Upstream is the network/address to dial, e.g. "tcp/127.0.0.1:9000".
+	Upstream string `json:"upstream,omitempty"`
+
+	// ConnectionPool opts into reusing idle upstream connections. Leaving it
+	// unset dials a fresh upstream connection per accepted client.
+	ConnectionPool *PoolConfig `json:"connection_pool,omitempty"`
+
+	network string
+	address string
+	pool    *Pool
+}
+
+// CaddyModule returns the Caddy module information.
+func (*Proxy) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "layer4.handlers.proxy",
+		New: func() caddy.Module { return new(Proxy) },
+	}
+}
+
+// Provision resolves the upstream dial target and, if ConnectionPool is set,
+// builds the connection pool.
+func (p *Proxy) Provision(_ caddy.Context) error {
+	network, address, found := strings.Cut(p.Upstream, "/")
+	if !found {
+		network, address = "tcp", p.Upstream
+	}
+	if address == "" {
+		return fmt.Errorf("upstream is required")
+	}
+	p.network, p.address = network, address
+
+	if p.ConnectionPool != nil {
+		p.pool = NewPool(
+			p.ConnectionPool.MaxIdleConns,
+			p.ConnectionPool.MaxIdleConnsPerHost,
+			time.Duration(p.ConnectionPool.IdleConnTimeout),
+		)
+	}
+
+	return nil
+}
+
+// Cleanup closes the connection pool, if one is configured, so pooled
+// upstreams don't outlive a config reload or shutdown.
+func (p *Proxy) Cleanup() error {
+	if p.pool == nil {
+		return nil
+	}
+	return p.pool.Close()
+}
+
+// Handle implements layer4.NextHandler. It dials (or, with ConnectionPool
+// configured, reuses) a connection to Upstream and relays bytes between it
+// and cx until either side closes.
+func (p *Proxy) Handle(cx *layer4.Connection, _ layer4.Handler) error {
+	upstreamConn, err := p.dial()
+	if err != nil {
+		return fmt.Errorf("dialing upstream %s/%s: %w", p.network, p.address, err)
+	}
+
+	returnedToPool := false
+	defer func() {
+		if !returnedToPool {
+			upstreamConn.Close()
+		}
+	}()
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(upstreamConn, cx)
+		errs <- err
+	}()
+	go func() {
+		_, err := io.Copy(cx, upstreamConn)
+		errs <- err
+	}()
+	err1 := <-errs
+	err2 := <-errs
+
+	// Reusing a connection after a full-duplex relay is only safe for
+	// pipelined/probing upstream protocols, which is exactly the opt-in
+	// short-lived-flow case the pool targets; both directions must report a
+	// clean (nil) result, since a half-drained or already-errored upstream
+	// socket must never be handed to a future, unrelated client.
+	if p.pool != nil && err1 == nil && err2 == nil {
+		returnedToPool = p.pool.Put(p.key(), upstreamConn)
+	}
+
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+func (p *Proxy) dial() (net.Conn, error) {
+	if p.pool != nil {
+		if conn, ok := p.pool.Get(p.key()); ok {
+			return conn, nil
+		}
+	}
+	return net.DialTimeout(p.network, p.address, defaultDialTimeout)
+}
+
+func (p *Proxy) key() PoolKey {
+	return KeyFor(p.network, p.address, nil)
+}
+
+// Interface guards
+var (
+	_ caddy.Module       = (*Proxy)(nil)
+	_ caddy.Provisioner  = (*Proxy)(nil)
+	_ caddy.CleanerUpper = (*Proxy)(nil)
+	_ layer4.NextHandler = (*Proxy)(nil)
+)